@@ -0,0 +1,115 @@
+package datasegment
+
+import (
+	"github.com/filecoin-project/go-data-segment/fr32"
+	"github.com/filecoin-project/go-data-segment/merkletree"
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// Aggregator incrementally builds an aggregator deal (CommDA) as client
+// pieces arrive. It maintains a merkletree.CachedTree of already-CommP'd
+// client subtrees in parallel with a second CachedTree of their data
+// segment index entries, so CommDA does not need to be rebuilt from
+// scratch - via GrowTree over every piece's raw data - each time a new
+// piece is added. All added pieces must be the same padded size, since
+// CachedTree assembles subtree roots of a single, fixed height.
+type Aggregator struct {
+	subtrees *merkletree.CachedTree
+	index    *merkletree.CachedTree
+	pieces   []addedPiece
+}
+
+type addedPiece struct {
+	commPc cid.Cid
+	sizePc abi.PaddedPieceSize
+	offset uint64
+}
+
+// NewAggregator creates an empty Aggregator.
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		subtrees: merkletree.NewCachedTree(nil),
+		index:    merkletree.NewCachedTree(nil),
+	}
+}
+
+// AddPiece registers a client's already-sealed piece, identified by its
+// CommPc and padded size, as the next subtree of the aggregator's deal.
+func (a *Aggregator) AddPiece(commPc cid.Cid, sizePc abi.PaddedPieceSize) error {
+	raw, err := commcid.CIDToPieceCommitmentV1(commPc)
+	if err != nil {
+		return xerrors.Errorf("invalid piece commitment: %w", err)
+	}
+	nodeCommPc := (*merkletree.Node)(raw)
+
+	var offset uint64
+	if len(a.pieces) > 0 {
+		last := a.pieces[len(a.pieces)-1]
+		offset = last.offset + uint64(last.sizePc)
+	}
+
+	// TODO: check overflow
+	height := log2Ceil(uint64(sizePc) / uint64(BytesInNode))
+	if err := a.subtrees.PushSubtree(nodeCommPc, height); err != nil {
+		return xerrors.Errorf("adding piece subtree: %w", err)
+	}
+
+	en, err := MakeDataSegmentIndexEntry((*fr32.Fr32)(nodeCommPc), offset, uint64(sizePc))
+	if err != nil {
+		return xerrors.Errorf("creating data segment index entry: %w", err)
+	}
+	if err := a.index.PushSubtree(merkletree.TruncatedHash(en.SerializeFr32()), 0); err != nil {
+		return xerrors.Errorf("adding index entry: %w", err)
+	}
+
+	a.pieces = append(a.pieces, addedPiece{commPc: commPc, sizePc: sizePc, offset: offset})
+	return nil
+}
+
+// Finalize returns the aggregator's CommPa, SizePa, and one InclusionProof
+// per piece added via AddPiece, in the order the pieces were added.
+func (a *Aggregator) Finalize() (cid.Cid, abi.PaddedPieceSize, []InclusionProof, error) {
+	if len(a.pieces) == 0 {
+		return cid.Undef, 0, nil, xerrors.Errorf("no pieces have been added")
+	}
+
+	root, err := a.subtrees.Root()
+	if err != nil {
+		return cid.Undef, 0, nil, xerrors.Errorf("computing aggregator root: %w", err)
+	}
+	cidPa, err := commcid.PieceCommitmentV1ToCID(root[:])
+	if err != nil {
+		return cid.Undef, 0, nil, xerrors.Errorf("converting raw commitment to CID: %w", err)
+	}
+
+	lvl := log2Ceil(uint64(len(a.pieces)))
+	// TODO: check overflow
+	sizePa := abi.PaddedPieceSize(uint64(1<<lvl) * uint64(a.pieces[0].sizePc))
+
+	proofs := make([]InclusionProof, len(a.pieces))
+	for i := range a.pieces {
+		subtreeProof, err := a.subtrees.ConstructProof(lvl, i)
+		if err != nil {
+			return cid.Undef, 0, nil, xerrors.Errorf("constructing subtree proof for piece %d: %w", i, err)
+		}
+		indexProof, err := a.index.ConstructProof(lvl, i)
+		if err != nil {
+			return cid.Undef, 0, nil, xerrors.Errorf("constructing index proof for piece %d: %w", i, err)
+		}
+		proofs[i] = InclusionProof{ProofSubtree: subtreeProof, ProofIndex: indexProof}
+	}
+
+	return cidPa, sizePa, proofs, nil
+}
+
+// log2Ceil computes the smallest n such that 2^n >= value.
+func log2Ceil(value uint64) int {
+	var n int
+	for (uint64(1) << n) < value {
+		n++
+	}
+	return n
+}
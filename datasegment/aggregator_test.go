@@ -0,0 +1,63 @@
+package datasegment
+
+import (
+	"testing"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+)
+
+// fakeCommPc returns a deterministic, validly Fr32-masked piece commitment
+// CID for test data; it doesn't need to come from a real sealed piece, only
+// to round-trip through commcid the way one would.
+func fakeCommPc(t *testing.T, seed byte) (cid.Cid, abi.PaddedPieceSize) {
+	t.Helper()
+	var raw [32]byte
+	for i := range raw {
+		raw[i] = seed + byte(i)
+	}
+	raw[31] &= 0b00111111
+	commPc, err := commcid.PieceCommitmentV1ToCID(raw[:])
+	if err != nil {
+		t.Fatalf("PieceCommitmentV1ToCID: %v", err)
+	}
+	return commPc, 64
+}
+
+// TestAggregatorFinalizeNonPowerOfTwo checks that Finalize produces a valid
+// InclusionProof for every piece even when the piece count is not a power
+// of two, which is exactly the case where Aggregator's underlying
+// CachedTree has pending, not-yet-collapsed mountain-range entries rather
+// than a single complete tree.
+func TestAggregatorFinalizeNonPowerOfTwo(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 6} {
+		agg := NewAggregator()
+		verifiers := make([]InclusionVerifierData, n)
+		for i := 0; i < n; i++ {
+			commPc, sizePc := fakeCommPc(t, byte(i+1))
+			if err := agg.AddPiece(commPc, sizePc); err != nil {
+				t.Fatalf("n=%d piece=%d: AddPiece: %v", n, i, err)
+			}
+			verifiers[i] = InclusionVerifierData{CommPc: commPc, SizePc: sizePc}
+		}
+
+		commPa, sizePa, proofs, err := agg.Finalize()
+		if err != nil {
+			t.Fatalf("n=%d: Finalize: %v", n, err)
+		}
+		if len(proofs) != n {
+			t.Fatalf("n=%d: got %d proofs, want %d", n, len(proofs), n)
+		}
+
+		for i, proof := range proofs {
+			aux, err := proof.ComputeExpectedAuxData(verifiers[i])
+			if err != nil {
+				t.Fatalf("n=%d piece=%d: ComputeExpectedAuxData: %v", n, i, err)
+			}
+			if aux.CommPa != commPa || aux.SizePa != sizePa {
+				t.Fatalf("n=%d piece=%d: aux data %+v does not match aggregator's %v/%d", n, i, aux, commPa, sizePa)
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package datasegment
+
+import (
+	"github.com/filecoin-project/go-data-segment/fr32"
+	"github.com/filecoin-project/go-data-segment/merkletree"
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+	"golang.org/x/xerrors"
+)
+
+// BatchedInclusionProof lets a client verify inclusion of many data segments
+// against a single aggregator deal in one pass. It is the datasegment-layer
+// analogue of merkletree.BatchedMerkleProof: rather than validating each
+// InclusionProof independently, all entries are checked against one another
+// so that SPs validating hundreds of client segments per sealed sector don't
+// have to treat disagreement as a per-entry surprise.
+type BatchedInclusionProof struct {
+	// Proofs holds one InclusionProof per client data segment, in the same
+	// order as the InclusionVerifierData slice passed to ComputeExpectedAuxData.
+	Proofs []InclusionProof
+}
+
+// batchedProofEntry pairs a single entry's proof with the leaf it is a
+// proof of, so foldSharedRoot doesn't need to care whether it is folding
+// subtree proofs or data segment index proofs.
+type batchedProofEntry struct {
+	proof merkletree.ProofData
+	leaf  *merkletree.Node
+}
+
+// ComputeExpectedAuxData verifies inclusion of every entry in verifierData
+// against a single CommPa/SizePa and returns an error if any entry's derived
+// CommPa/SizePa disagrees with the others.
+//
+// Unlike calling InclusionProof.ComputeExpectedAuxData once per entry, the
+// upper path every entry's proof shares with an already-processed entry -
+// the same idea TreeData.ConstructBatchedProof exploits as commonPath for a
+// contiguous range of leafs - is hashed only once: see foldSharedRoot.
+func (bp BatchedInclusionProof) ComputeExpectedAuxData(verifierData []InclusionVerifierData) (*InclusionAuxData, error) {
+	if len(bp.Proofs) != len(verifierData) {
+		return nil, xerrors.Errorf("expected %d proofs, got %d verifier entries", len(bp.Proofs), len(verifierData))
+	}
+	if len(bp.Proofs) == 0 {
+		return nil, xerrors.Errorf("no entries to verify")
+	}
+
+	subtreeEntries := make([]batchedProofEntry, len(bp.Proofs))
+	indexEntries := make([]batchedProofEntry, len(bp.Proofs))
+	var assumedSizePa, assumedSizePa2 uint64
+
+	for i, verifier := range verifierData {
+		commPc, err := commcid.CIDToPieceCommitmentV1(verifier.CommPc)
+		if err != nil {
+			return nil, xerrors.Errorf("entry %d: invalid piece commitment: %w", i, err)
+		}
+		nodeCommPc := (*merkletree.Node)(commPc)
+		subtreeEntries[i] = batchedProofEntry{proof: bp.Proofs[i].ProofSubtree, leaf: nodeCommPc}
+
+		// TODO: check overflow
+		dataOffset := bp.Proofs[i].ProofSubtree.Index() * uint64(verifier.SizePc)
+		en, err := MakeDataSegmentIndexEntry((*fr32.Fr32)(nodeCommPc), dataOffset, uint64(verifier.SizePc))
+		if err != nil {
+			return nil, xerrors.Errorf("entry %d: creating data segment index entry: %w", i, err)
+		}
+		indexEntries[i] = batchedProofEntry{proof: bp.Proofs[i].ProofIndex, leaf: merkletree.TruncatedHash(en.SerializeFr32())}
+
+		// TODO: check overflow
+		entrySizePa := uint64(1<<bp.Proofs[i].ProofSubtree.Depth()) * uint64(verifier.SizePc)
+		const BytesInDataSegmentIndexEntry = 2 * BytesInNode
+		entrySizePa2 := uint64(1<<bp.Proofs[i].ProofIndex.Depth()) * uint64(BytesInDataSegmentIndexEntry)
+		if i == 0 {
+			assumedSizePa, assumedSizePa2 = entrySizePa, entrySizePa2
+		} else if entrySizePa != assumedSizePa || entrySizePa2 != assumedSizePa2 {
+			return nil, xerrors.Errorf("entry %d: aggregator's data size doesn't match the other entries", i)
+		}
+	}
+	if assumedSizePa != assumedSizePa2 {
+		return nil, xerrors.Errorf("aggregator's data size doesn't match")
+	}
+
+	subtreeRoot, err := foldSharedRoot(subtreeEntries)
+	if err != nil {
+		return nil, xerrors.Errorf("folding subtree proofs: %w", err)
+	}
+	indexRoot, err := foldSharedRoot(indexEntries)
+	if err != nil {
+		return nil, xerrors.Errorf("folding index proofs: %w", err)
+	}
+	if *subtreeRoot != *indexRoot {
+		return nil, xerrors.Errorf("aggregator's data commitments don't match: %x != %x", subtreeRoot, indexRoot)
+	}
+
+	cidPa, err := commcid.PieceCommitmentV1ToCID(subtreeRoot[:])
+	if err != nil {
+		return nil, xerrors.Errorf("converting raw commitment to CID: %w", err)
+	}
+	return &InclusionAuxData{CommPa: cidPa, SizePa: abi.PaddedPieceSize(assumedSizePa)}, nil
+}
+
+// foldSharedRoot combines every entry's leaf with its proof's sibling path
+// up to the aggregator root, hashing each ancestor node at most once across
+// all entries rather than once per entry.
+//
+// remainder memoizes, for an ancestor position (level from the root and
+// index) a previous entry's climb already passed through, the node that
+// climb had there and the root that continuing up from it produces. Once a
+// later entry's own climb reaches such a position with the same node, the
+// rest of its path to the root - and every HashNode call along it - is
+// already known, so the climb stops there instead of re-hashing a path some
+// earlier entry already paid for; a different node there is an error.
+func foldSharedRoot(entries []batchedProofEntry) (*merkletree.Node, error) {
+	if len(entries) == 0 {
+		return nil, xerrors.Errorf("no entries to fold")
+	}
+
+	type ancestorKey struct {
+		lvl int
+		idx uint64
+	}
+	type climbed struct {
+		node merkletree.Node
+		root merkletree.Node
+	}
+	remainder := make(map[ancestorKey]climbed, len(entries))
+
+	var root *merkletree.Node
+	for i, entry := range entries {
+		hasher, ok := merkletree.HasherByID(entry.proof.HasherID())
+		if !ok {
+			return nil, xerrors.Errorf("entry %d: unknown hasher id %d in proof", i, entry.proof.HasherID())
+		}
+		lvl := entry.proof.Depth()
+		path := entry.proof.Path()
+		if len(path) != lvl {
+			return nil, xerrors.Errorf("entry %d: proof level %d does not match path length %d", i, lvl, len(path))
+		}
+
+		idx := entry.proof.Index()
+		node := *entry.leaf
+		visited := make([]ancestorKey, 0, lvl)
+		visitedNodes := make([]merkletree.Node, 0, lvl)
+		for height := 0; height < lvl; height++ {
+			key := ancestorKey{lvl: lvl - height, idx: idx}
+			if known, ok := remainder[key]; ok {
+				if known.node != node {
+					return nil, xerrors.Errorf("entry %d: aggregator's data commitments don't match at level %d, index %d", i, key.lvl, idx)
+				}
+				node = known.root
+				visited = nil
+				break
+			}
+			visited = append(visited, key)
+			visitedNodes = append(visitedNodes, node)
+			sibling := path[lvl-1-height]
+			switch {
+			case sibling == (merkletree.Node{}):
+				// No sibling: the lone child is hashed as its own parent,
+				// mirroring merkletree.ProofData.ValidateSubtree.
+				node = hasher.HashLeaf(node[:])
+			case idx%2 == 0:
+				node = hasher.HashNode(&node, &sibling)
+			default:
+				node = hasher.HashNode(&sibling, &node)
+			}
+			idx /= 2
+		}
+		for j, key := range visited {
+			remainder[key] = climbed{node: visitedNodes[j], root: node}
+		}
+
+		if root == nil {
+			root = &node
+		} else if *root != node {
+			return nil, xerrors.Errorf("entry %d: aggregator's data commitments don't match: %x != %x", i, node, *root)
+		}
+	}
+	return root, nil
+}
@@ -0,0 +1,90 @@
+package datasegment
+
+import "testing"
+
+// TestBatchedInclusionProofMatchesPerEntry checks that
+// BatchedInclusionProof.ComputeExpectedAuxData, which folds every entry's
+// shared upper path via foldSharedRoot, agrees with calling
+// InclusionProof.ComputeExpectedAuxData once per entry - including for a
+// non-power-of-two piece count, where some proofs have a "no sibling" step
+// foldSharedRoot must self-hash rather than mistakenly combine with a
+// missing sibling.
+func TestBatchedInclusionProofMatchesPerEntry(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 7} {
+		agg := NewAggregator()
+		verifiers := make([]InclusionVerifierData, n)
+		for i := 0; i < n; i++ {
+			commPc, sizePc := fakeCommPc(t, byte(10*i+1))
+			if err := agg.AddPiece(commPc, sizePc); err != nil {
+				t.Fatalf("n=%d piece=%d: AddPiece: %v", n, i, err)
+			}
+			verifiers[i] = InclusionVerifierData{CommPc: commPc, SizePc: sizePc}
+		}
+
+		_, _, proofs, err := agg.Finalize()
+		if err != nil {
+			t.Fatalf("n=%d: Finalize: %v", n, err)
+		}
+
+		batched := BatchedInclusionProof{Proofs: proofs}
+		batchedAux, err := batched.ComputeExpectedAuxData(verifiers)
+		if err != nil {
+			t.Fatalf("n=%d: BatchedInclusionProof.ComputeExpectedAuxData: %v", n, err)
+		}
+
+		for i, proof := range proofs {
+			aux, err := proof.ComputeExpectedAuxData(verifiers[i])
+			if err != nil {
+				t.Fatalf("n=%d piece=%d: ComputeExpectedAuxData: %v", n, i, err)
+			}
+			if aux.CommPa != batchedAux.CommPa || aux.SizePa != batchedAux.SizePa {
+				t.Fatalf("n=%d piece=%d: batched aux %+v disagrees with per-entry aux %+v", n, i, batchedAux, aux)
+			}
+		}
+	}
+}
+
+// TestBatchedInclusionProofRejectsMismatch checks that mixing in a proof
+// from an unrelated aggregator - so its derived CommPa disagrees with the
+// rest of the batch - is rejected rather than silently folded in.
+func TestBatchedInclusionProofRejectsMismatch(t *testing.T) {
+	agg := NewAggregator()
+	verifiers := make([]InclusionVerifierData, 3)
+	for i := 0; i < 3; i++ {
+		commPc, sizePc := fakeCommPc(t, byte(20*i+1))
+		if err := agg.AddPiece(commPc, sizePc); err != nil {
+			t.Fatalf("AddPiece: %v", err)
+		}
+		verifiers[i] = InclusionVerifierData{CommPc: commPc, SizePc: sizePc}
+	}
+	_, _, proofs, err := agg.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+
+	other := NewAggregator()
+	otherCommPc, otherSizePc := fakeCommPc(t, 200)
+	if err := other.AddPiece(otherCommPc, otherSizePc); err != nil {
+		t.Fatalf("AddPiece (other): %v", err)
+	}
+	otherCommPc2, otherSizePc2 := fakeCommPc(t, 210)
+	if err := other.AddPiece(otherCommPc2, otherSizePc2); err != nil {
+		t.Fatalf("AddPiece (other): %v", err)
+	}
+	// A third piece gives the other aggregator the same depth and SizePa,
+	// so only the commitments disagree.
+	otherCommPc3, otherSizePc3 := fakeCommPc(t, 220)
+	if err := other.AddPiece(otherCommPc3, otherSizePc3); err != nil {
+		t.Fatalf("AddPiece (other): %v", err)
+	}
+	_, _, otherProofs, err := other.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize (other): %v", err)
+	}
+
+	mixed := BatchedInclusionProof{Proofs: append(append([]InclusionProof(nil), proofs...), otherProofs[0])}
+	mixedVerifiers := append(append([]InclusionVerifierData(nil), verifiers...), InclusionVerifierData{CommPc: otherCommPc, SizePc: otherSizePc})
+	if _, err := mixed.ComputeExpectedAuxData(mixedVerifiers); err == nil {
+		t.Fatal("expected mixing in a proof from an unrelated aggregator to be rejected")
+	}
+}
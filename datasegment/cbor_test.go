@@ -0,0 +1,51 @@
+package datasegment
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestInclusionProofRoundTripCBOR mirrors merkletree.ProofData's own CBOR
+// round-trip test one level up: InclusionProof bundles two ProofData values
+// (subtree and index), across non-power-of-two piece counts where some
+// proofs carry a "no sibling" step.
+func TestInclusionProofRoundTripCBOR(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 6} {
+		agg := NewAggregator()
+		verifiers := make([]InclusionVerifierData, n)
+		for i := 0; i < n; i++ {
+			commPc, sizePc := fakeCommPc(t, byte(30*i+1))
+			if err := agg.AddPiece(commPc, sizePc); err != nil {
+				t.Fatalf("n=%d piece=%d: AddPiece: %v", n, i, err)
+			}
+			verifiers[i] = InclusionVerifierData{CommPc: commPc, SizePc: sizePc}
+		}
+		_, _, proofs, err := agg.Finalize()
+		if err != nil {
+			t.Fatalf("n=%d: Finalize: %v", n, err)
+		}
+
+		for i, proof := range proofs {
+			var buf bytes.Buffer
+			if err := proof.MarshalCBOR(&buf); err != nil {
+				t.Fatalf("n=%d piece=%d: MarshalCBOR: %v", n, i, err)
+			}
+			var decoded InclusionProof
+			if err := decoded.UnmarshalCBOR(&buf); err != nil {
+				t.Fatalf("n=%d piece=%d: UnmarshalCBOR: %v", n, i, err)
+			}
+
+			origAux, err := proof.ComputeExpectedAuxData(verifiers[i])
+			if err != nil {
+				t.Fatalf("n=%d piece=%d: original proof: ComputeExpectedAuxData: %v", n, i, err)
+			}
+			decodedAux, err := decoded.ComputeExpectedAuxData(verifiers[i])
+			if err != nil {
+				t.Fatalf("n=%d piece=%d: round-tripped proof: ComputeExpectedAuxData: %v", n, i, err)
+			}
+			if decodedAux.CommPa != origAux.CommPa || decodedAux.SizePa != origAux.SizePa {
+				t.Fatalf("n=%d piece=%d: round-tripped proof disagrees with the original", n, i)
+			}
+		}
+	}
+}
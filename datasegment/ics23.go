@@ -0,0 +1,70 @@
+package datasegment
+
+import (
+	ics23 "github.com/cosmos/ics23/go"
+	"github.com/filecoin-project/go-data-segment/merkletree"
+	"golang.org/x/xerrors"
+)
+
+// ToICS23 encodes the InclusionProof as an ICS-23 CommitmentProof batch of
+// two existence proofs: the client's data segment subtree proof and the
+// data segment index entry proof. leafSubtree and leafIndex are the
+// pre-image data each proof was constructed against (the client's CommPc
+// and the serialized data segment index entry, respectively).
+//
+// This lets an inclusion proof be carried in ICS-23's wire format and
+// decoded back with FromInclusionICS23, but it is not a standards-compliant
+// ICS-23 proof: see the warning on merkletree.ProofData.ToICS23. A generic
+// ICS-23 verifier - a real IBC-connected chain's light client, for instance
+// - recomputes inner hashes as plain SHA-256 and will reject these proofs,
+// because the underlying tree's HashNode clears the top two bits of every
+// node it hashes and ICS-23 has no operation to express that.
+func (ip InclusionProof) ToICS23(leafSubtree []byte, leafIndex []byte) (*ics23.CommitmentProof, error) {
+	subtreeProof, err := ip.ProofSubtree.ToICS23(leafSubtree)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding subtree proof: %w", err)
+	}
+	indexProof, err := ip.ProofIndex.ToICS23(leafIndex)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding index proof: %w", err)
+	}
+	return &ics23.CommitmentProof{
+		Proof: &ics23.CommitmentProof_Batch{
+			Batch: &ics23.BatchProof{
+				Entries: []*ics23.BatchEntry{
+					{Proof: &ics23.BatchEntry_Exist{Exist: subtreeProof}},
+					{Proof: &ics23.BatchEntry_Exist{Exist: indexProof}},
+				},
+			},
+		},
+	}, nil
+}
+
+// FromInclusionICS23 is the inverse of ToICS23: it decodes the two batched
+// existence proofs back into an InclusionProof. subtreeLvl and indexLvl
+// must be supplied by the caller, since an ICS-23 proof does not record the
+// tree's level numbering the way merkletree.ProofData does.
+func FromInclusionICS23(proof *ics23.CommitmentProof, subtreeLvl int, indexLvl int) (*InclusionProof, error) {
+	batch, ok := proof.Proof.(*ics23.CommitmentProof_Batch)
+	if !ok || len(batch.Batch.Entries) != 2 {
+		return nil, xerrors.Errorf("expected a 2-entry ics23 batch proof")
+	}
+	subtreeExist, ok := batch.Batch.Entries[0].Proof.(*ics23.BatchEntry_Exist)
+	if !ok {
+		return nil, xerrors.Errorf("first batch entry is not an existence proof")
+	}
+	indexExist, ok := batch.Batch.Entries[1].Proof.(*ics23.BatchEntry_Exist)
+	if !ok {
+		return nil, xerrors.Errorf("second batch entry is not an existence proof")
+	}
+
+	subtreeProof, err := merkletree.FromICS23(subtreeExist.Exist, subtreeLvl)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding subtree proof: %w", err)
+	}
+	indexProof, err := merkletree.FromICS23(indexExist.Exist, indexLvl)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding index proof: %w", err)
+	}
+	return &InclusionProof{ProofSubtree: *subtreeProof, ProofIndex: *indexProof}, nil
+}
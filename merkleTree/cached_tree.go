@@ -0,0 +1,252 @@
+package merkleTree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// cacheKey identifies a node cached by CachedTree: height is the number of
+// levels above the pushed-subtree level (0 is a pushed subtree root itself),
+// and idx is that node's index within its height, both assigned in the same
+// left-to-right order PushSubtree calls arrive in.
+type cacheKey struct {
+	height int
+	idx    int
+}
+
+// pushedSubtree is one entry of a CachedTree's mountain range: a pushed (or
+// already-combined) subtree root together with its height above the pushed
+// level.
+type pushedSubtree struct {
+	root   Node
+	height int
+}
+
+// CachedTree holds the already-computed roots of subtree pieces pushed via
+// PushSubtree (e.g. one already-CommP'd client piece per push) and
+// assembles them into CommDA, so aggregators building a deal as new client
+// pieces arrive don't have to call GrowTree over all the raw piece data
+// again on every addition. Like StreamingTree, it keeps a compact "mountain
+// range" of at most one pending subtree root per height (floor(log2(pushed))+1
+// in all) and combines two of
+// equal height as soon as they appear; every combined node is memoized in a
+// cache keyed by (height, idx), so PushSubtree does O(log N) work, not O(N),
+// and Root/ConstructProof never re-fold more than the current mountain
+// range. It is modeled after Sia's cached-tree API.
+type CachedTree struct {
+	hasher    Hasher
+	height    int
+	heightSet bool
+	pushed    int
+	pending   []pushedSubtree
+	// levelCount[h] tracks how many nodes of height h have been emitted so
+	// far, giving the deterministic index to store the next one at.
+	levelCount []int
+	cache      map[cacheKey]Node
+}
+
+// NewCachedTree creates an empty CachedTree. If hasher is nil, the
+// package's default Hasher (Fr32-truncated SHA-256) is used, preserving
+// existing CommP semantics.
+func NewCachedTree(hasher Hasher) *CachedTree {
+	if hasher == nil {
+		hasher = defaultHasher
+	}
+	return &CachedTree{hasher: hasher, cache: make(map[cacheKey]Node)}
+}
+
+// PushSubtree appends root as the next subtree, at height above the leaf
+// level. Every root pushed to a given CachedTree must share the same
+// height, since they represent fixed-size client pieces. PushSubtree does
+// O(log N) work: it combines root with at most one pending subtree per
+// height, caching every combined node it produces along the way.
+func (c *CachedTree) PushSubtree(root *Node, height int) error {
+	if c.heightSet && height != c.height {
+		return fmt.Errorf("cached tree expects subtree roots at height %d, got %d", c.height, height)
+	}
+	c.height = height
+	c.heightSet = true
+	c.pushed++
+	if err := c.emit(*root, 0); err != nil {
+		return err
+	}
+	return c.collapse()
+}
+
+// emit stores node at the given height at its next deterministic index and
+// pushes it onto the mountain range as the most recently produced subtree.
+func (c *CachedTree) emit(node Node, height int) error {
+	for height >= len(c.levelCount) {
+		c.levelCount = append(c.levelCount, 0)
+	}
+	idx := c.levelCount[height]
+	c.levelCount[height]++
+	c.cache[cacheKey{height: height, idx: idx}] = node
+	c.pending = append(c.pending, pushedSubtree{root: node, height: height})
+	return nil
+}
+
+// collapse repeatedly combines the two most recently pushed subtrees while
+// they share the same height, so the mountain range never holds more than
+// one pending root per height.
+func (c *CachedTree) collapse() error {
+	for len(c.pending) >= 2 {
+		top := c.pending[len(c.pending)-1]
+		second := c.pending[len(c.pending)-2]
+		if top.height != second.height {
+			break
+		}
+		combined := *computeNode(&second.root, &top.root, c.hasher)
+		c.pending = c.pending[:len(c.pending)-2]
+		if err := c.emit(combined, top.height+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// foldPending non-destructively combines a copy of pending into a single
+// root, exactly as StreamingTree.Finalize does for a trailing subtree
+// without an equal-height sibling: it becomes its own parent via
+// hasher.HashLeaf until a single root remains. The entries pending holds are
+// always in strictly descending height order, left to right (the same
+// invariant a binary counter's set bits have), so this is also exactly the
+// binary-counter carry-propagation sequence: the rightmost (smallest)
+// entry is repeatedly self-promoted until its height catches up to its
+// left neighbour, then the two combine, and so on leftward.
+//
+// If track is a valid index into pending, it identifies the entry whose
+// climb to the root the caller wants to observe: onStep is called every
+// time that entry takes part in a step, with the height it climbed from
+// and the sibling it combined with (nil when it was instead self-promoted
+// with no sibling), letting ConstructProof record a proof path without
+// duplicating this folding logic.
+func foldPending(pending []pushedSubtree, hasher Hasher, track int, onStep func(height int, sibling *Node)) Node {
+	pending = append([]pushedSubtree(nil), pending...)
+	for len(pending) > 1 {
+		lastPos := len(pending) - 1
+		top := pending[lastPos]
+		second := pending[lastPos-1]
+		if top.height == second.height {
+			// Both lastPos and lastPos-1 are consumed by the combine, so
+			// either one being tracked means the result (the new last
+			// entry) is tracked afterwards.
+			combined := *computeNode(&second.root, &top.root, hasher)
+			consumed := track == lastPos || track == lastPos-1
+			if track == lastPos {
+				onStep(top.height, &second.root)
+			} else if track == lastPos-1 {
+				onStep(top.height, &top.root)
+			}
+			pending = pending[:lastPos-1]
+			pending = append(pending, pushedSubtree{root: combined, height: top.height + 1})
+			if consumed {
+				track = len(pending) - 1
+			}
+		} else {
+			// Only lastPos (top) is promoted; second keeps its own
+			// position untouched, so only track == lastPos moves.
+			promoted := *computeLeafParent(&top.root, hasher)
+			consumed := track == lastPos
+			if consumed {
+				onStep(top.height, nil)
+			}
+			pending = pending[:lastPos]
+			pending = append(pending, pushedSubtree{root: promoted, height: top.height + 1})
+			if consumed {
+				track = len(pending) - 1
+			}
+		}
+	}
+	return pending[0].root
+}
+
+// fold non-destructively folds the current mountain range into a single
+// root; see foldPending. Unlike collapse, the result is not permanent -
+// more subtrees may still be pushed, at which point a previously-lone
+// entry may gain a real sibling instead - so the nodes it produces are not
+// written to the cache.
+func (c *CachedTree) fold() (Node, error) {
+	if len(c.pending) == 0 {
+		return Node{}, errors.New("cached tree is empty")
+	}
+	return foldPending(c.pending, c.hasher, -1, nil), nil
+}
+
+// Root returns CommDA for the subtree roots pushed so far. It can be called
+// again after more subtrees are pushed.
+func (c *CachedTree) Root() (*Node, error) {
+	root, err := c.fold()
+	if err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// ConstructProof constructs a Merkle proof of the subtree root pushed at
+// index idx against the current Root, where lvl is the tree's current depth
+// (log2Ceil of the number of subtrees pushed so far). Below the height of
+// the pending entry that currently contains idx, every sibling is a real,
+// already-combined node and is served straight from the cache PushSubtree
+// populated. Above that, idx's node is still part of the mountain range's
+// pending entries rather than settled into the cache - since a later push
+// could still give it a real sibling - so that portion of the path is
+// produced by replaying the same fold the other pending entries would go
+// through to reach the root, via foldPending. Either way this is O(log N),
+// not the O(N) a fresh GrowTree over every pushed subtree would cost.
+func (c *CachedTree) ConstructProof(lvl int, idx int) (ProofData, error) {
+	if len(c.pending) == 0 {
+		return ProofData{}, errors.New("cached tree is empty")
+	}
+	wantLvl := log2Ceil(c.pushed)
+	if lvl != wantLvl {
+		return ProofData{}, fmt.Errorf("level %d does not match the cached tree's current depth %d", lvl, wantLvl)
+	}
+	if idx < 0 || idx >= c.pushed {
+		return ProofData{}, fmt.Errorf("index %d is out of range for %d pushed subtrees", idx, c.pushed)
+	}
+
+	path := make([]Node, lvl)
+
+	// Locate the pending entry whose subtree currently covers idx: pending
+	// entries are contiguous, left to right, in push order.
+	entryPos := -1
+	entryHeight := 0
+	remaining := idx
+	for i, p := range c.pending {
+		width := 1 << p.height
+		if remaining < width {
+			entryPos = i
+			entryHeight = p.height
+			break
+		}
+		remaining -= width
+	}
+	if entryPos == -1 {
+		return ProofData{}, fmt.Errorf("index %d is not covered by the current mountain range", idx)
+	}
+
+	// Inside that entry, every level is a complete, already-collapsed
+	// binary subtree, so every sibling is a real node the cache has.
+	currentIdx := idx
+	for height := 0; height < entryHeight; height++ {
+		sibIdx := getSiblingIdx(currentIdx)
+		sibling, ok := c.cache[cacheKey{height: height, idx: sibIdx}]
+		if !ok {
+			return ProofData{}, fmt.Errorf("internal error: cache missing sibling at height %d, index %d", height, sibIdx)
+		}
+		path[lvl-1-height] = sibling
+		currentIdx /= 2
+	}
+
+	// Above entryHeight, idx's node only exists as part of the mountain
+	// range's still-open pending entries; replay their fold to the root and
+	// record the siblings (or lack of one) idx's node meets along the way.
+	foldPending(c.pending, c.hasher, entryPos, func(height int, sibling *Node) {
+		if sibling != nil {
+			path[lvl-1-height] = *sibling
+		}
+	})
+
+	return ProofData{path: path, lvl: lvl, idx: idx, hasherID: c.hasher.ID()}, nil
+}
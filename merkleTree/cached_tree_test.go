@@ -0,0 +1,97 @@
+package merkleTree
+
+import "testing"
+
+// TestCachedTreeMatchesGrowTree checks that a CachedTree fed one subtree
+// root at a time, including non-power-of-two counts, agrees with building
+// the same subtree roots into a tree with GrowTree directly.
+func TestCachedTreeMatchesGrowTree(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 8, 11} {
+		leafs := leafData(n)
+		subtreeRoots := hashList(leafs, defaultHasher)
+		want := growTreeHashedLeafs(subtreeRoots, defaultHasher)
+
+		cached := NewCachedTree(nil)
+		for i := range subtreeRoots {
+			if err := cached.PushSubtree(&subtreeRoots[i], 0); err != nil {
+				t.Fatalf("n=%d: PushSubtree: %v", n, err)
+			}
+		}
+
+		root, err := cached.Root()
+		if err != nil {
+			t.Fatalf("n=%d: Root: %v", n, err)
+		}
+		if *root != *want.GetRoot() {
+			t.Fatalf("n=%d: cached root %x != GrowTree root %x", n, root.data, want.GetRoot().data)
+		}
+
+		lvl := log2Ceil(n)
+		for idx := 0; idx < n; idx++ {
+			proof, err := cached.ConstructProof(lvl, idx)
+			if err != nil {
+				t.Fatalf("n=%d idx=%d: ConstructProof: %v", n, idx, err)
+			}
+			if !proof.ValidateLeaf(leafs[idx], root) {
+				t.Fatalf("n=%d idx=%d: cached proof failed to validate", n, idx)
+			}
+		}
+	}
+}
+
+// TestCachedTreeGrowsAcrossQueries checks that querying Root/ConstructProof
+// mid-stream, then pushing more subtrees, keeps proofs for the later state
+// correct - i.e. the tree genuinely tracks the running set of pushed
+// subtrees rather than freezing at the first query.
+func TestCachedTreeGrowsAcrossQueries(t *testing.T) {
+	leafs := leafData(4)
+	subtreeRoots := hashList(leafs, defaultHasher)
+
+	cached := NewCachedTree(nil)
+	for i := 0; i < 2; i++ {
+		if err := cached.PushSubtree(&subtreeRoots[i], 0); err != nil {
+			t.Fatalf("PushSubtree: %v", err)
+		}
+	}
+	if _, err := cached.Root(); err != nil {
+		t.Fatalf("Root after 2 pushes: %v", err)
+	}
+
+	for i := 2; i < 4; i++ {
+		if err := cached.PushSubtree(&subtreeRoots[i], 0); err != nil {
+			t.Fatalf("PushSubtree: %v", err)
+		}
+	}
+
+	want := growTreeHashedLeafs(subtreeRoots, defaultHasher)
+	root, err := cached.Root()
+	if err != nil {
+		t.Fatalf("Root after 4 pushes: %v", err)
+	}
+	if *root != *want.GetRoot() {
+		t.Fatalf("cached root %x != GrowTree root %x after growing past an earlier query", root.data, want.GetRoot().data)
+	}
+	for idx := 0; idx < 4; idx++ {
+		proof, err := cached.ConstructProof(log2Ceil(4), idx)
+		if err != nil {
+			t.Fatalf("idx=%d: ConstructProof: %v", idx, err)
+		}
+		if !proof.ValidateLeaf(leafs[idx], root) {
+			t.Fatalf("idx=%d: proof failed to validate after growing past an earlier query", idx)
+		}
+	}
+}
+
+// TestCachedTreeRejectsMixedHeights checks that pushing subtrees of
+// different heights, which would not represent fixed-size client pieces, is
+// rejected.
+func TestCachedTreeRejectsMixedHeights(t *testing.T) {
+	cached := NewCachedTree(nil)
+	root := defaultHasher.HashLeaf([]byte("a"))
+	if err := cached.PushSubtree(&root, 2); err != nil {
+		t.Fatalf("PushSubtree: %v", err)
+	}
+	if err := cached.PushSubtree(&root, 3); err == nil {
+		t.Fatal("expected pushing a subtree at a different height to fail")
+	}
+}
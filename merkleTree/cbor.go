@@ -0,0 +1,219 @@
+package merkleTree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MarshalBinary encodes the proof as lvl, idx and hasherID, followed by an
+// explicit presence bitmap (one bit per path entry, LSB-first within each
+// byte) and then only the sibling digests that are actually present.
+//
+// Earlier callers relied on the all-zero [digestBytes]byte array as an
+// in-band sentinel for "this sibling does not exist; hash the single child
+// instead". That convention is only safe in memory: once a proof is
+// shipped between an aggregator and a verifier, a peer that strips a
+// missing entry, or a tree whose legitimate node happens to collide with
+// the all-zero value, would silently corrupt it. Encoding presence
+// separately from the path means a stripped entry can no longer be mistaken
+// for a missing sibling on the wire. The bitmap itself is still derived from
+// the in-memory all-zero sentinel, so a sibling whose legitimate value is
+// all-zero is encoded, and decoded, as missing.
+func (d ProofData) MarshalBinary() ([]byte, error) {
+	if d.lvl < 0 {
+		return nil, errors.New("invalid proof: negative level")
+	}
+	if len(d.path) != d.lvl {
+		return nil, fmt.Errorf("invalid proof: level %d does not match path length %d", d.lvl, len(d.path))
+	}
+
+	bitmapLen := (d.lvl + 7) / 8
+	buf := make([]byte, 0, 4+8+1+bitmapLen+len(d.path)*digestBytes)
+
+	var lvlBuf [4]byte
+	binary.BigEndian.PutUint32(lvlBuf[:], uint32(d.lvl))
+	buf = append(buf, lvlBuf[:]...)
+
+	var idxBuf [8]byte
+	binary.BigEndian.PutUint64(idxBuf[:], uint64(d.idx))
+	buf = append(buf, idxBuf[:]...)
+
+	buf = append(buf, byte(d.hasherID))
+
+	bitmap := make([]byte, bitmapLen)
+	present := make([]Node, 0, len(d.path))
+	for i, node := range d.path {
+		if node.data != [digestBytes]byte{} {
+			bitmap[i/8] |= 1 << uint(i%8)
+			present = append(present, node)
+		}
+	}
+	buf = append(buf, bitmap...)
+	for _, node := range present {
+		buf = append(buf, node.data[:]...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary is the inverse of MarshalBinary.
+func (d *ProofData) UnmarshalBinary(data []byte) error {
+	if len(data) < 4+8+1 {
+		return errors.New("proof data too short")
+	}
+	lvl := int(binary.BigEndian.Uint32(data[0:4]))
+	idx := int(binary.BigEndian.Uint64(data[4:12]))
+	hasherID := HasherID(data[12])
+	rest := data[13:]
+
+	// No real tree has anywhere near this many levels; bounding lvl before
+	// it is used to size allocations below prevents a malicious proof from
+	// claiming an enormous level count to force an out-of-memory allocation
+	// from a small message.
+	const maxProofLevels = 1024
+	if lvl < 0 || lvl > maxProofLevels {
+		return fmt.Errorf("proof data has an implausible level %d", lvl)
+	}
+	bitmapLen := (lvl + 7) / 8
+	if len(rest) < bitmapLen {
+		return errors.New("proof data truncated: missing presence bitmap")
+	}
+	bitmap := rest[:bitmapLen]
+	rest = rest[bitmapLen:]
+
+	path := make([]Node, lvl)
+	for i := 0; i < lvl; i++ {
+		if bitmap[i/8]&(1<<uint(i%8)) == 0 {
+			continue
+		}
+		if len(rest) < digestBytes {
+			return errors.New("proof data truncated: missing sibling digest")
+		}
+		copy(path[i].data[:], rest[:digestBytes])
+		rest = rest[digestBytes:]
+	}
+
+	d.lvl = lvl
+	d.idx = idx
+	d.hasherID = hasherID
+	d.path = path
+	return nil
+}
+
+// MarshalCBOR writes the CBOR encoding of the proof to w, as a single CBOR
+// byte string wrapping MarshalBinary's output.
+func (d ProofData) MarshalCBOR(w io.Writer) error {
+	data, err := d.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return writeCBORByteString(w, data)
+}
+
+// UnmarshalCBOR is the inverse of MarshalCBOR.
+func (d *ProofData) UnmarshalCBOR(r io.Reader) error {
+	data, err := readCBORByteString(r)
+	if err != nil {
+		return err
+	}
+	return d.UnmarshalBinary(data)
+}
+
+// writeCBORByteString writes data to w as a CBOR major type 2 (byte string)
+// value.
+func writeCBORByteString(w io.Writer, data []byte) error {
+	if err := writeCBORHeader(w, 2, uint64(len(data))); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func writeCBORHeader(w io.Writer, majorType byte, length uint64) error {
+	switch {
+	case length < 24:
+		_, err := w.Write([]byte{majorType<<5 | byte(length)})
+		return err
+	case length <= 0xff:
+		_, err := w.Write([]byte{majorType<<5 | 24, byte(length)})
+		return err
+	case length <= 0xffff:
+		buf := make([]byte, 3)
+		buf[0] = majorType<<5 | 25
+		binary.BigEndian.PutUint16(buf[1:], uint16(length))
+		_, err := w.Write(buf)
+		return err
+	case length <= 0xffffffff:
+		buf := make([]byte, 5)
+		buf[0] = majorType<<5 | 26
+		binary.BigEndian.PutUint32(buf[1:], uint32(length))
+		_, err := w.Write(buf)
+		return err
+	default:
+		buf := make([]byte, 9)
+		buf[0] = majorType<<5 | 27
+		binary.BigEndian.PutUint64(buf[1:], length)
+		_, err := w.Write(buf)
+		return err
+	}
+}
+
+// readCBORByteString reads a CBOR major type 2 (byte string) value from r.
+func readCBORByteString(r io.Reader) ([]byte, error) {
+	var head [1]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	majorType := head[0] >> 5
+	if majorType != 2 {
+		return nil, fmt.Errorf("expected a CBOR byte string (major type 2), got major type %d", majorType)
+	}
+
+	info := head[0] & 0x1f
+	var length uint64
+	switch {
+	case info < 24:
+		length = uint64(info)
+	case info == 24:
+		var b [1]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(b[0])
+	case info == 25:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case info == 26:
+		var b [4]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint32(b[:]))
+	case info == 27:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(b[:])
+	default:
+		return nil, fmt.Errorf("unsupported CBOR length encoding (additional info %d)", info)
+	}
+
+	// A proof is never remotely this large; bounding length before it is
+	// used to size an allocation prevents a malicious or truncated header
+	// from making a handful of bytes claim an allocation of gigabytes.
+	const maxByteStringLength = 1 << 24 // 16MiB
+	if length > maxByteStringLength {
+		return nil, fmt.Errorf("CBOR byte string length %d exceeds the %d byte limit", length, maxByteStringLength)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,139 @@
+package merkleTree
+
+import (
+	"bytes"
+	"testing"
+)
+
+func leafData(n int) [][]byte {
+	data := make([][]byte, n)
+	for i := range data {
+		data[i] = []byte{byte(i), byte(i >> 8), 0xAA}
+	}
+	return data
+}
+
+// TestProofRoundTripBinary covers non-power-of-two leaf counts, where some
+// proof entries hit the "no sibling" case and must round-trip through the
+// presence bitmap rather than the path itself.
+func TestProofRoundTripBinary(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 7, 8, 9, 16, 17} {
+		data := leafData(n)
+		tree, err := GrowTree(data)
+		if err != nil {
+			t.Fatalf("leafs=%d: GrowTree: %v", n, err)
+		}
+		root := tree.GetRoot()
+		for idx := 0; idx < n; idx++ {
+			proof, err := tree.ConstructProof(tree.Depth()-1, idx)
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: ConstructProof: %v", n, idx, err)
+			}
+
+			encoded, err := proof.MarshalBinary()
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: MarshalBinary: %v", n, idx, err)
+			}
+			var decoded ProofData
+			if err := decoded.UnmarshalBinary(encoded); err != nil {
+				t.Fatalf("leafs=%d idx=%d: UnmarshalBinary: %v", n, idx, err)
+			}
+			if !decoded.ValidateLeaf(data[idx], root) {
+				t.Fatalf("leafs=%d idx=%d: round-tripped proof failed to validate", n, idx)
+			}
+		}
+	}
+}
+
+// TestProofRoundTripCBOR exercises the CBOR wrapper on top of MarshalBinary,
+// again across non-power-of-two leaf counts.
+func TestProofRoundTripCBOR(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 6, 13} {
+		data := leafData(n)
+		tree, err := GrowTree(data)
+		if err != nil {
+			t.Fatalf("leafs=%d: GrowTree: %v", n, err)
+		}
+		root := tree.GetRoot()
+		for idx := 0; idx < n; idx++ {
+			proof, err := tree.ConstructProof(tree.Depth()-1, idx)
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: ConstructProof: %v", n, idx, err)
+			}
+
+			var buf bytes.Buffer
+			if err := proof.MarshalCBOR(&buf); err != nil {
+				t.Fatalf("leafs=%d idx=%d: MarshalCBOR: %v", n, idx, err)
+			}
+			var decoded ProofData
+			if err := decoded.UnmarshalCBOR(&buf); err != nil {
+				t.Fatalf("leafs=%d idx=%d: UnmarshalCBOR: %v", n, idx, err)
+			}
+			if !decoded.ValidateLeaf(data[idx], root) {
+				t.Fatalf("leafs=%d idx=%d: CBOR round-tripped proof failed to validate", n, idx)
+			}
+		}
+	}
+}
+
+// TestProofBitmapMarksMissingSiblings checks that, for an odd leaf count,
+// the lone trailing leaf's proof has at least one "no sibling" step and that
+// MarshalBinary/UnmarshalBinary preserve exactly which steps those are via
+// the presence bitmap, rather than relying on an all-zero digest sentinel.
+func TestProofBitmapMarksMissingSiblings(t *testing.T) {
+	data := leafData(5)
+	tree, err := GrowTree(data)
+	if err != nil {
+		t.Fatalf("GrowTree: %v", err)
+	}
+	lastIdx := len(data) - 1
+	proof, err := tree.ConstructProof(tree.Depth()-1, lastIdx)
+	if err != nil {
+		t.Fatalf("ConstructProof: %v", err)
+	}
+
+	var sawMissing bool
+	for _, node := range proof.path {
+		if node.data == [digestBytes]byte{} {
+			sawMissing = true
+			break
+		}
+	}
+	if !sawMissing {
+		t.Fatal("expected the lone trailing leaf's proof to have a missing-sibling step")
+	}
+
+	encoded, err := proof.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	var decoded ProofData
+	if err := decoded.UnmarshalBinary(encoded); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	for i := range proof.path {
+		if decoded.path[i] != proof.path[i] {
+			t.Fatalf("path entry %d did not round-trip: got %x, want %x", i, decoded.path[i].data, proof.path[i].data)
+		}
+	}
+	if !decoded.ValidateLeaf(data[lastIdx], tree.GetRoot()) {
+		t.Fatal("round-tripped proof with a missing sibling failed to validate")
+	}
+}
+
+// TestSingleLeafTreeHasNoProof documents the degenerate single-leaf case:
+// the root is the leaf itself, so there is no level above it to prove
+// against.
+func TestSingleLeafTreeHasNoProof(t *testing.T) {
+	data := leafData(1)
+	tree, err := GrowTree(data)
+	if err != nil {
+		t.Fatalf("GrowTree: %v", err)
+	}
+	if got, want := *tree.GetRoot(), defaultHasher.HashLeaf(data[0]); got != want {
+		t.Fatalf("root = %x, want %x", got.data, want.data)
+	}
+	if _, err := tree.ConstructProof(tree.Depth()-1, 0); err == nil {
+		t.Fatal("expected ConstructProof to reject level 0 on a single-leaf tree")
+	}
+}
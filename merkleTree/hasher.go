@@ -0,0 +1,183 @@
+package merkleTree
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"lukechampine.com/blake3"
+
+	"github.com/iden3/go-iden3-crypto/poseidon"
+)
+
+// HasherID is a stable identifier for a Hasher implementation, serialized
+// alongside a ProofData so that a verifier can pick the matching Hasher
+// instead of assuming the tree was built with the package default.
+type HasherID uint8
+
+const (
+	// HasherFr32SHA256 is the default hasher: SHA-256 with the top two bits
+	// of the digest cleared so it fits in an Fr32 (BLS12-381 scalar) field
+	// element, preserving the existing CommP semantics.
+	HasherFr32SHA256 HasherID = iota
+	// HasherSHA256 is plain SHA-256 with RFC 6962-style domain separation
+	// (0x00 prefix for leafs, 0x01 prefix for internal nodes).
+	HasherSHA256
+	// HasherBLAKE3 hashes leafs and nodes with BLAKE3.
+	HasherBLAKE3
+	// HasherPoseidonBN254 hashes leafs and nodes with the classic Poseidon
+	// permutation over the BN254 scalar field (github.com/iden3/go-iden3-crypto),
+	// for zk-friendly commitments in circuits built over that curve. This is
+	// NOT Poseidon2, and NOT over BLS12-381 - a verifier that needs either of
+	// those must bring its own Hasher rather than relying on this one.
+	HasherPoseidonBN254
+)
+
+// Hasher abstracts the hash function used to build and verify a Merkle
+// tree. The package defaults to HasherFr32SHA256 so existing CommP
+// semantics are unchanged, but proof consumers building non-Filecoin trees
+// can plug in an alternative, e.g. Poseidon over BN254 for zk-friendly
+// commitments.
+type Hasher interface {
+	// HashLeaf hashes a single leaf's data into a Node.
+	HashLeaf(data []byte) Node
+	// HashNode combines two child nodes into their parent Node.
+	HashNode(l *Node, r *Node) Node
+	// Size returns the digest size in bytes produced by this Hasher.
+	Size() int
+	// ID returns the HasherID to serialize alongside proofs built with it.
+	ID() HasherID
+}
+
+// HasherByID returns the package-provided Hasher for id, or false if id is
+// not one the package knows how to construct (e.g. it came from a newer
+// version of this library).
+func HasherByID(id HasherID) (Hasher, bool) {
+	switch id {
+	case HasherFr32SHA256:
+		return fr32SHA256Hasher{}, true
+	case HasherSHA256:
+		return sha256Hasher{}, true
+	case HasherBLAKE3:
+		return blake3Hasher{}, true
+	case HasherPoseidonBN254:
+		return poseidonBN254Hasher{}, true
+	default:
+		return nil, false
+	}
+}
+
+// defaultHasher is used whenever a caller does not explicitly select one,
+// keeping the existing Fr32-truncated SHA-256 CommP semantics unchanged.
+var defaultHasher Hasher = fr32SHA256Hasher{}
+
+// fr32SHA256Hasher is the hasher this package has always used: SHA-256 with
+// the top two bits of the last byte cleared so the digest fits in an Fr32
+// element.
+type fr32SHA256Hasher struct{}
+
+func (fr32SHA256Hasher) HashLeaf(data []byte) Node {
+	return fr32SHA256Hasher{}.hash(data)
+}
+
+func (fr32SHA256Hasher) HashNode(l *Node, r *Node) Node {
+	toHash := make([]byte, 2*digestBytes)
+	copy(toHash, l.data[:])
+	copy(toHash[digestBytes:], r.data[:])
+	return fr32SHA256Hasher{}.hash(toHash)
+}
+
+func (fr32SHA256Hasher) hash(data []byte) Node {
+	digest := sha256.Sum256(data)
+	digest[(256/8)-1] &= 0b00111111
+	return Node{digest}
+}
+
+func (fr32SHA256Hasher) Size() int { return digestBytes }
+
+func (fr32SHA256Hasher) ID() HasherID { return HasherFr32SHA256 }
+
+// sha256Hasher is plain SHA-256 with the RFC 6962 domain separation
+// prefixes (0x00 for leafs, 0x01 for internal nodes), so proofs built with
+// it are directly comparable to other Certificate-Transparency-style logs.
+type sha256Hasher struct{}
+
+func (sha256Hasher) HashLeaf(data []byte) Node {
+	buf := make([]byte, 0, len(data)+1)
+	buf = append(buf, 0x00)
+	buf = append(buf, data...)
+	return Node{sha256.Sum256(buf)}
+}
+
+func (sha256Hasher) HashNode(l *Node, r *Node) Node {
+	buf := make([]byte, 0, 1+2*digestBytes)
+	buf = append(buf, 0x01)
+	buf = append(buf, l.data[:]...)
+	buf = append(buf, r.data[:]...)
+	return Node{sha256.Sum256(buf)}
+}
+
+func (sha256Hasher) Size() int { return digestBytes }
+
+func (sha256Hasher) ID() HasherID { return HasherSHA256 }
+
+// blake3Hasher hashes leafs and nodes with BLAKE3.
+type blake3Hasher struct{}
+
+func (blake3Hasher) HashLeaf(data []byte) Node {
+	var node Node
+	digest := blake3.Sum256(data)
+	copy(node.data[:], digest[:])
+	return node
+}
+
+func (blake3Hasher) HashNode(l *Node, r *Node) Node {
+	toHash := make([]byte, 2*digestBytes)
+	copy(toHash, l.data[:])
+	copy(toHash[digestBytes:], r.data[:])
+	var node Node
+	digest := blake3.Sum256(toHash)
+	copy(node.data[:], digest[:])
+	return node
+}
+
+func (blake3Hasher) Size() int { return digestBytes }
+
+func (blake3Hasher) ID() HasherID { return HasherBLAKE3 }
+
+// poseidonBN254Hasher hashes leafs and nodes with the classic Poseidon
+// permutation over the BN254 scalar field, via
+// github.com/iden3/go-iden3-crypto/poseidon. Despite the package's other
+// Hasher names evoking Filecoin's BLS12-381 field, this one is BN254-only;
+// it is meant for proof consumers building zk-friendly commitments in
+// circuits over that curve, not as a drop-in for Filecoin CommP trees.
+type poseidonBN254Hasher struct{}
+
+func (poseidonBN254Hasher) HashLeaf(data []byte) Node {
+	return poseidonBN254Hasher{}.hash(data)
+}
+
+func (poseidonBN254Hasher) HashNode(l *Node, r *Node) Node {
+	toHash := make([]byte, 2*digestBytes)
+	copy(toHash, l.data[:])
+	copy(toHash[digestBytes:], r.data[:])
+	return poseidonBN254Hasher{}.hash(toHash)
+}
+
+func (poseidonBN254Hasher) hash(data []byte) Node {
+	// poseidon.HashBytes internally splits data into BN254 field elements
+	// and folds them with Poseidon; the result is reduced modulo the BN254
+	// scalar field so it always fits in digestBytes.
+	digest, err := poseidon.HashBytes(data)
+	if err != nil {
+		// Poseidon only fails on malformed field elements, which HashBytes
+		// cannot produce from raw bytes; treat it as unreachable.
+		panic(fmt.Sprintf("poseidon hash: %v", err))
+	}
+	var node Node
+	digest.FillBytes(node.data[:])
+	return node
+}
+
+func (poseidonBN254Hasher) Size() int { return digestBytes }
+
+func (poseidonBN254Hasher) ID() HasherID { return HasherPoseidonBN254 }
@@ -0,0 +1,52 @@
+package merkleTree
+
+import "testing"
+
+func TestHasherByID(t *testing.T) {
+	ids := []HasherID{HasherFr32SHA256, HasherSHA256, HasherBLAKE3, HasherPoseidonBN254}
+	for _, id := range ids {
+		hasher, ok := HasherByID(id)
+		if !ok {
+			t.Fatalf("HasherByID(%d): not found", id)
+		}
+		if hasher.ID() != id {
+			t.Fatalf("HasherByID(%d): returned a Hasher identifying as %d", id, hasher.ID())
+		}
+	}
+	if _, ok := HasherByID(HasherID(255)); ok {
+		t.Fatal("HasherByID(255): expected an unknown id to report false")
+	}
+}
+
+// TestHasherTreeRoundTrip builds a tree with each Hasher and checks that a
+// constructed proof validates against the resulting root, and that the
+// hasherID threaded through ProofData picks the same Hasher back up.
+func TestHasherTreeRoundTrip(t *testing.T) {
+	hashers := []Hasher{fr32SHA256Hasher{}, sha256Hasher{}, blake3Hasher{}, poseidonBN254Hasher{}}
+	data := leafData(5)
+	for _, hasher := range hashers {
+		tree, err := GrowTreeWithHasher(data, hasher)
+		if err != nil {
+			t.Fatalf("hasher %d: GrowTreeWithHasher: %v", hasher.ID(), err)
+		}
+		for idx := range data {
+			proof, err := tree.ConstructProof(tree.Depth()-1, idx)
+			if err != nil {
+				t.Fatalf("hasher %d idx %d: ConstructProof: %v", hasher.ID(), idx, err)
+			}
+			if proof.hasherID != hasher.ID() {
+				t.Fatalf("hasher %d: proof recorded hasherID %d", hasher.ID(), proof.hasherID)
+			}
+			if !proof.ValidateLeaf(data[idx], tree.GetRoot()) {
+				t.Fatalf("hasher %d idx %d: proof failed to validate", hasher.ID(), idx)
+			}
+		}
+	}
+}
+
+func TestFr32HasherMasksTopBits(t *testing.T) {
+	node := fr32SHA256Hasher{}.HashLeaf([]byte("some data"))
+	if node.data[digestBytes-1]&0b11000000 != 0 {
+		t.Fatalf("expected the top two bits of the last byte to be cleared, got %08b", node.data[digestBytes-1])
+	}
+}
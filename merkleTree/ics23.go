@@ -0,0 +1,151 @@
+package merkleTree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	ics23 "github.com/cosmos/ics23/go"
+)
+
+// ToICS23 encodes the proof as an ICS-23 ExistenceProof for leaf, using the
+// wire format (and this package's own VerifyMembership) to round-trip a
+// Filecoin data-segment inclusion proof through ICS-23's CommitmentProof
+// types - e.g. to store or transmit it alongside other ICS-23 proofs. The
+// key is the big-endian encoding of the leaf's index.
+//
+// This is NOT a standards-compliant ICS-23 proof and a generic external
+// ICS-23 verifier (a real Cosmos-SDK light client, for instance) will
+// reject it, for two independent reasons, neither of which is specific to
+// the default Hasher:
+//
+//  1. fr32SHA256Hasher's HashNode clears the top two bits of every node's
+//     digest, leaf or internal, so it can't be expressed as a plain
+//     ics23.HashOp: the spec's LeafOp/InnerOp only let a verifier apply one
+//     of the standard hash functions to prefix/child/suffix bytes, with no
+//     way to post-process the result the way the mask requires.
+//  2. More fundamentally, a real LeafOp hashes Key together with Value
+//     (LeafOp.Apply(key, value) = hash(prefix||key||value)), because ICS-23
+//     existence proofs commit to key-value pairs. This package's leaf hash
+//     never incorporates the leaf's index - HashLeaf(data) only ever hashes
+//     the leaf bytes themselves, with the index carried structurally by the
+//     proof path rather than cryptographically bound into the digest. So
+//     even a non-masking Hasher such as sha256Hasher would hash the wrong
+//     bytes (key||value instead of just value) under a real verifier.
+//
+// Because of (2), switching the default hasher out for sha256Hasher would
+// not make the result any more portable, so this only supports exporting a
+// proof built with the default Hasher. The LeafOp and every InnerOp below
+// are declared as bare SHA-256 and the masking is instead reapplied
+// out-of-band by FromICS23/VerifyMembership in this file, which makes the
+// proof round-trip correctly through this package but only this package -
+// it is not, and cannot currently be made, portable to another ICS-23
+// implementation.
+func (d ProofData) ToICS23(leaf []byte) (*ics23.ExistenceProof, error) {
+	if d.hasherID != HasherFr32SHA256 {
+		return nil, fmt.Errorf("ICS-23 export is only supported for the default Fr32-truncated SHA-256 hasher, got hasher id %d", d.hasherID)
+	}
+
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(d.idx))
+
+	path := make([]*ics23.InnerOp, 0, len(d.path))
+	idx := d.idx
+	for lvl := d.lvl; lvl >= 1; lvl-- {
+		sibling := d.path[lvl-1]
+		op := &ics23.InnerOp{Hash: ics23.HashOp_SHA256}
+		switch {
+		case sibling.data == [digestBytes]byte{}:
+			// No sibling: the lone child is hashed as its own parent. There is
+			// no sibling bytes to place in Prefix/Suffix, so both are left empty
+			// and FromICS23/VerifyMembership recognize this InnerOp by its
+			// length to re-derive the single-child rule.
+		case getSiblingIdx(idx)%2 == 1:
+			// Sibling is to the right of currentNode.
+			op.Suffix = append([]byte(nil), sibling.data[:]...)
+		default:
+			// Sibling is to the left of currentNode.
+			op.Prefix = append([]byte(nil), sibling.data[:]...)
+		}
+		path = append(path, op)
+		idx = idx / 2
+	}
+
+	return &ics23.ExistenceProof{
+		Key:   key,
+		Value: leaf,
+		Leaf: &ics23.LeafOp{
+			Hash:         ics23.HashOp_SHA256,
+			PrehashKey:   ics23.HashOp_NO_HASH,
+			PrehashValue: ics23.HashOp_NO_HASH,
+			Length:       ics23.LengthOp_NO_PREFIX,
+		},
+		Path: path,
+	}, nil
+}
+
+// FromICS23 is the inverse of ToICS23: it rebuilds a ProofData (using the
+// default Fr32-truncated SHA-256 Hasher) from proof, taking the leaf index
+// from the proof's key. lvl must be supplied by the caller because an ICS-23
+// ExistenceProof, unlike ProofData, does not record the tree's level
+// numbering.
+func FromICS23(proof *ics23.ExistenceProof, lvl int) (*ProofData, error) {
+	if proof.Leaf == nil {
+		return nil, errors.New("ics23 existence proof has no leaf op")
+	}
+	if proof.Leaf.Hash != ics23.HashOp_SHA256 || proof.Leaf.PrehashValue != ics23.HashOp_NO_HASH || proof.Leaf.Length != ics23.LengthOp_NO_PREFIX {
+		return nil, errors.New("ics23 existence proof is not in the bare-SHA-256 leaf encoding this package produces")
+	}
+	if len(proof.Key) != 8 {
+		return nil, fmt.Errorf("expected an 8-byte big-endian index key, got %d bytes", len(proof.Key))
+	}
+	idx := int(binary.BigEndian.Uint64(proof.Key))
+	if lvl < 1 || lvl != len(proof.Path) {
+		return nil, fmt.Errorf("level %d does not match the %d inner ops in the proof", lvl, len(proof.Path))
+	}
+
+	path := make([]Node, lvl)
+	currentIdx := idx
+	for i, op := range proof.Path {
+		currentLvl := lvl - i
+		var sibling Node
+		switch {
+		case len(op.Prefix) == 0 && len(op.Suffix) == 0:
+			// No sibling: leave the all-zero sentinel in place.
+		case len(op.Suffix) > 0:
+			copy(sibling.data[:], op.Suffix)
+		default:
+			copy(sibling.data[:], op.Prefix)
+		}
+		path[currentLvl-1] = sibling
+		currentIdx = currentIdx / 2
+	}
+
+	return &ProofData{path: path, lvl: lvl, idx: idx, hasherID: HasherFr32SHA256}, nil
+}
+
+// VerifyMembership checks that leaf is included at the index encoded in
+// proof and at level lvl, validating against root. It is the ICS-23-facing
+// counterpart to ProofData.ValidateLeaf, going through FromICS23 so callers
+// holding only the wire-format proof (e.g. after storing it alongside other
+// ICS-23 proofs and reading it back) can still validate it against this
+// package's tree semantics.
+//
+// This deliberately does not call the generic ics23.VerifyMembership: as
+// explained on ToICS23, the InnerOps here describe bare SHA-256, not this
+// package's Fr32-masked hashing, so the generic verifier would recompute
+// different node values than the tree actually has at every level above
+// the leaf and reject the proof. Re-deriving a ProofData via FromICS23 and
+// validating it with this package's own (masked) logic is what actually
+// matches how the tree was built; it proves the proof survived the ICS-23
+// encoding round-trip, not that it is portable to another ICS-23 verifier.
+func VerifyMembership(proof *ics23.ExistenceProof, lvl int, root *Node, leaf []byte) (bool, error) {
+	proofData, err := FromICS23(proof, lvl)
+	if err != nil {
+		return false, fmt.Errorf("decoding ics23 proof: %w", err)
+	}
+	if string(proof.Value) != string(leaf) {
+		return false, errors.New("leaf does not match the value committed to in the ics23 proof")
+	}
+	return proofData.ValidateLeaf(leaf, root), nil
+}
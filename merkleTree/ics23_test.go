@@ -0,0 +1,62 @@
+package merkleTree
+
+import "testing"
+
+// TestICS23RoundTrip covers non-power-of-two leaf counts, so both the
+// "has a sibling" and "lone, no sibling" InnerOp encodings in ToICS23 are
+// exercised.
+func TestICS23RoundTrip(t *testing.T) {
+	for _, n := range []int{2, 3, 5, 8, 9} {
+		data := leafData(n)
+		tree, err := GrowTree(data)
+		if err != nil {
+			t.Fatalf("leafs=%d: GrowTree: %v", n, err)
+		}
+		root := tree.GetRoot()
+		for idx := 0; idx < n; idx++ {
+			proof, err := tree.ConstructProof(tree.Depth()-1, idx)
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: ConstructProof: %v", n, idx, err)
+			}
+
+			existence, err := proof.ToICS23(data[idx])
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: ToICS23: %v", n, idx, err)
+			}
+
+			decoded, err := FromICS23(existence, tree.Depth()-1)
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: FromICS23: %v", n, idx, err)
+			}
+			if !decoded.ValidateLeaf(data[idx], root) {
+				t.Fatalf("leafs=%d idx=%d: proof decoded from ICS-23 failed to validate", n, idx)
+			}
+
+			ok, err := VerifyMembership(existence, tree.Depth()-1, root, data[idx])
+			if err != nil {
+				t.Fatalf("leafs=%d idx=%d: VerifyMembership: %v", n, idx, err)
+			}
+			if !ok {
+				t.Fatalf("leafs=%d idx=%d: VerifyMembership reported the proof as invalid", n, idx)
+			}
+		}
+	}
+}
+
+// TestICS23RejectsNonDefaultHasher checks that ToICS23 refuses to export a
+// proof built with a Hasher other than the default, since the masking
+// work-around it relies on only holds for HasherFr32SHA256.
+func TestICS23RejectsNonDefaultHasher(t *testing.T) {
+	data := leafData(3)
+	tree, err := GrowTreeWithHasher(data, blake3Hasher{})
+	if err != nil {
+		t.Fatalf("GrowTreeWithHasher: %v", err)
+	}
+	proof, err := tree.ConstructProof(tree.Depth()-1, 0)
+	if err != nil {
+		t.Fatalf("ConstructProof: %v", err)
+	}
+	if _, err := proof.ToICS23(data[0]); err == nil {
+		t.Fatal("expected ToICS23 to reject a proof built with a non-default Hasher")
+	}
+}
@@ -1,7 +1,6 @@
 package merkleTree
 
 import (
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log"
@@ -35,6 +34,9 @@ type TreeData struct {
 	// nodes start from root and go down left-to-right
 	// thus len(nodes[0]) = 1, len(nodes[1]) = 2, etc.
 	nodes [][]Node
+	// hasher is the Hasher the tree was built with, and is reused to
+	// construct proofs against it.
+	hasher Hasher
 }
 
 type Node struct {
@@ -55,6 +57,10 @@ type ProofData struct {
 	// idx indicates the index within the level where the element whose membership to prove is located
 	// Leftmost node is index 0
 	idx int
+	// hasherID identifies the Hasher the owning tree was built with, so it
+	// can be serialized alongside the proof and a verifier can pick the
+	// matching implementation rather than assuming the package default.
+	hasherID HasherID
 }
 
 // Depth returns the amount of levels in the tree, including the root level and leafs.
@@ -72,7 +78,7 @@ func (d TreeData) GetRoot() *Node {
 }
 
 func (d TreeData) ValidateFromLeafs(leafs [][]byte) bool {
-	tree, err := GrowTree(leafs)
+	tree, err := GrowTreeWithHasher(leafs, d.hasher)
 	if err != nil {
 		log.Println("could not grow tree")
 		return false
@@ -81,12 +87,19 @@ func (d TreeData) ValidateFromLeafs(leafs [][]byte) bool {
 }
 
 func (d TreeData) Validate() bool {
-	tree := growTreeHashedLeafs(d.nodes[d.Depth()-1])
+	tree := growTreeHashedLeafs(d.nodes[d.Depth()-1], d.hasher)
 	return reflect.DeepEqual(d.nodes, tree.nodes)
 }
 
 func NewBareTree(elements int) TreeData {
+	return NewBareTreeWithHasher(elements, defaultHasher)
+}
+
+// NewBareTreeWithHasher is like NewBareTree but builds a tree that will use
+// hasher, instead of the package default, when leafs and nodes are hashed.
+func NewBareTreeWithHasher(elements int, hasher Hasher) TreeData {
 	var tree TreeData
+	tree.hasher = hasher
 	tree.nodes = make([][]Node, 1+log2Ceil(elements))
 	for i := 0; i <= log2Ceil(elements); i++ {
 		tree.nodes[i] = make([]Node, 1<<i)
@@ -94,17 +107,26 @@ func NewBareTree(elements int) TreeData {
 	return tree
 }
 
+// GrowTree builds a tree from leafData using the package's default Hasher
+// (Fr32-truncated SHA-256), preserving the existing CommP semantics.
 func GrowTree(leafData [][]byte) (TreeData, error) {
+	return GrowTreeWithHasher(leafData, defaultHasher)
+}
+
+// GrowTreeWithHasher is like GrowTree but hashes leafs and nodes with
+// hasher, e.g. to build a zk-friendly tree with a Poseidon Hasher instead
+// of a Filecoin CommP tree.
+func GrowTreeWithHasher(leafData [][]byte, hasher Hasher) (TreeData, error) {
 	var tree TreeData
 	if leafData == nil || len(leafData) == 0 {
 		return tree, errors.New("empty input")
 	}
-	leafLevel := hashList(leafData)
-	return growTreeHashedLeafs(leafLevel), nil
+	leafLevel := hashList(leafData, hasher)
+	return growTreeHashedLeafs(leafLevel, hasher), nil
 }
 
-func growTreeHashedLeafs(leafs []Node) TreeData {
-	tree := NewBareTree(len(leafs))
+func growTreeHashedLeafs(leafs []Node, hasher Hasher) TreeData {
+	tree := NewBareTreeWithHasher(len(leafs), hasher)
 	// Set the leaf nodes
 	tree.nodes[log2Ceil(len(leafs))] = leafs
 	preLevel := leafs
@@ -114,12 +136,12 @@ func growTreeHashedLeafs(leafs []Node) TreeData {
 		currentLevel := make([]Node, halfCeil(len(preLevel)))
 		// Traverse the level left to right
 		for i := 0; i+1 < len(preLevel); i = i + 2 {
-			currentLevel[i/2] = *computeNode(&preLevel[i], &preLevel[i+1])
+			currentLevel[i/2] = *computeNode(&preLevel[i], &preLevel[i+1], hasher)
 		}
 		// Handle the edge case where the tree is not complete, i.e. there is an odd number of leafs
 		// This is done by hashing the content of the node and letting it be its own parent
 		if len(preLevel)%2 == 1 {
-			currentLevel[halfCeil(len(preLevel))-1] = *truncatedHash(preLevel[len(preLevel)-1].data[:])
+			currentLevel[halfCeil(len(preLevel))-1] = hasher.HashLeaf(preLevel[len(preLevel)-1].data[:])
 		}
 		tree.nodes[level] = currentLevel
 		preLevel = currentLevel
@@ -154,7 +176,7 @@ func (d TreeData) ConstructProof(lvl int, idx int) (ProofData, error) {
 		// Set next index to be the parent
 		currentIdx = currentIdx / 2
 	}
-	return ProofData{path: proof, lvl: lvl, idx: idx}, nil
+	return ProofData{path: proof, lvl: lvl, idx: idx, hasherID: d.hasher.ID()}, nil
 }
 
 func (d TreeData) ConstructBatchedProof(leftLvl int, leftIdx int, rightLvl int, rightIdx int) (BatchedMerkleProof, error) {
@@ -191,11 +213,21 @@ func (d TreeData) ConstructBatchedProof(leftLvl int, leftIdx int, rightLvl int,
 }
 
 func (d ProofData) ValidateLeaf(data []byte, root *Node) bool {
-	leaf := truncatedHash(data)
-	return d.ValidateSubtree(leaf, root)
+	hasher, ok := HasherByID(d.hasherID)
+	if !ok {
+		log.Println(fmt.Sprintf("unknown hasher id %d in proof", d.hasherID))
+		return false
+	}
+	leaf := hasher.HashLeaf(data)
+	return d.ValidateSubtree(&leaf, root)
 }
 
 func (d ProofData) ValidateSubtree(subtree *Node, root *Node) bool {
+	hasher, ok := HasherByID(d.hasherID)
+	if !ok {
+		log.Println(fmt.Sprintf("unknown hasher id %d in proof", d.hasherID))
+		return false
+	}
 	currentNode := subtree
 	currentIdx := d.idx
 	var parent *Node
@@ -207,13 +239,13 @@ func (d ProofData) ValidateSubtree(subtree *Node, root *Node) bool {
 		// will only happen with negligible probability
 		if sibling.data == [digestBytes]byte{} {
 			// In case the node does not exist, the only child will be hashed
-			parent = truncatedHash(currentNode.data[:])
+			parent = computeLeafParent(currentNode, hasher)
 		} else {
 			// If the sibling is "right" then we must hash currentNode first
 			if sibIdx%2 == 1 {
-				parent = computeNode(currentNode, &sibling)
+				parent = computeNode(currentNode, &sibling, hasher)
 			} else {
-				parent = computeNode(&sibling, currentNode)
+				parent = computeNode(&sibling, currentNode, hasher)
 			}
 		}
 		currentNode = parent
@@ -237,25 +269,31 @@ func getSiblingIdx(idx int) int {
 	}
 }
 
-func computeNode(left *Node, right *Node) *Node {
-	toHash := make([]byte, 2*digestBytes)
-	copy(toHash, (*left).data[:])
-	copy(toHash[digestBytes:], (*right).data[:])
-	return truncatedHash(toHash)
+func computeNode(left *Node, right *Node, hasher Hasher) *Node {
+	node := hasher.HashNode(left, right)
+	return &node
+}
+
+// computeLeafParent hashes a single child to stand in for its own parent,
+// used when a sibling does not exist because the tree is not complete.
+func computeLeafParent(child *Node, hasher Hasher) *Node {
+	node := hasher.HashLeaf(child.data[:])
+	return &node
 }
 
-func hashList(input [][]byte) []Node {
+func hashList(input [][]byte, hasher Hasher) []Node {
 	digests := make([]Node, len(input))
 	for i := 0; i < len(input); i++ {
-		digests[i] = *truncatedHash(input[i])
+		digests[i] = hasher.HashLeaf(input[i])
 	}
 	return digests
 }
 
+// truncatedHash hashes data with the package's default Hasher
+// (Fr32-truncated SHA-256), preserving the existing CommP semantics for
+// callers that have not opted into an alternative Hasher.
 func truncatedHash(data []byte) *Node {
-	digst := sha256.Sum256(data)
-	digst[(256/8)-1] &= 0b00111111
-	node := Node{digst}
+	node := defaultHasher.HashLeaf(data)
 	return &node
 }
 
@@ -0,0 +1,316 @@
+package merkleTree
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// NodeStore abstracts the storage of computed tree nodes, keyed by level and
+// index, so a tree can be built without keeping every node resident in RAM.
+// Level 0 is the root and index 0 is the left-most node in a level,
+// consistent with TreeData.
+type NodeStore interface {
+	// PutNode stores the node found at the given level and index.
+	PutNode(lvl int, idx int, node Node) error
+	// GetNode retrieves the node previously stored at the given level and index.
+	GetNode(lvl int, idx int) (Node, error)
+	// Close releases any resources (e.g. open file descriptors) held by the
+	// store. It is safe to call on a NodeStore that holds no such resources.
+	Close() error
+}
+
+// memoryNodeStore is the default NodeStore, keeping every level in memory.
+// It is used when a StreamingTree is not given an on-disk backing.
+type memoryNodeStore struct {
+	nodes [][]Node
+}
+
+func newMemoryNodeStore(depth int) *memoryNodeStore {
+	return &memoryNodeStore{nodes: make([][]Node, depth)}
+}
+
+func (m *memoryNodeStore) PutNode(lvl int, idx int, node Node) error {
+	if idx >= len(m.nodes[lvl]) {
+		grown := make([]Node, idx+1)
+		copy(grown, m.nodes[lvl])
+		m.nodes[lvl] = grown
+	}
+	m.nodes[lvl][idx] = node
+	return nil
+}
+
+func (m *memoryNodeStore) GetNode(lvl int, idx int) (Node, error) {
+	if lvl < 0 || lvl >= len(m.nodes) || idx < 0 || idx >= len(m.nodes[lvl]) {
+		return Node{}, errors.New("node not found in store")
+	}
+	return m.nodes[lvl][idx], nil
+}
+
+// Close is a no-op: memoryNodeStore holds no external resources.
+func (m *memoryNodeStore) Close() error { return nil }
+
+// fileNodeStore streams tree levels to a file. Since the amount of leafs is
+// known up front, the depth and size of every level are fixed, so the byte
+// offset of any (lvl, idx) pair is deterministic and nodes can be written at
+// their final offset as soon as they are computed, without ever buffering
+// the full tree in memory.
+type fileNodeStore struct {
+	f            *os.File
+	levelOffsets []int64
+}
+
+// NewFileNodeStore creates a NodeStore backed by the file at path, sized to
+// hold a tree built from expectedLeaves leafs.
+func NewFileNodeStore(path string, expectedLeaves int) (NodeStore, error) {
+	if expectedLeaves <= 0 {
+		return nil, errors.New("expected leafs must be positive")
+	}
+	depth := 1 + log2Ceil(expectedLeaves)
+	sizes := make([]int, depth)
+	for lvl := 0; lvl < depth-1; lvl++ {
+		sizes[lvl] = 1 << lvl
+	}
+	sizes[depth-1] = expectedLeaves
+
+	offsets := make([]int64, depth)
+	var total int64
+	for lvl := 0; lvl < depth; lvl++ {
+		offsets[lvl] = total
+		total += int64(sizes[lvl]) * digestBytes
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating node store file: %w", err)
+	}
+	if err := f.Truncate(total); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sizing node store file: %w", err)
+	}
+	return &fileNodeStore{f: f, levelOffsets: offsets}, nil
+}
+
+func (fs *fileNodeStore) offset(lvl int, idx int) int64 {
+	return fs.levelOffsets[lvl] + int64(idx)*digestBytes
+}
+
+func (fs *fileNodeStore) PutNode(lvl int, idx int, node Node) error {
+	if lvl < 0 || lvl >= len(fs.levelOffsets) {
+		return errors.New("level out of range for node store")
+	}
+	_, err := fs.f.WriteAt(node.data[:], fs.offset(lvl, idx))
+	return err
+}
+
+func (fs *fileNodeStore) GetNode(lvl int, idx int) (Node, error) {
+	if lvl < 0 || lvl >= len(fs.levelOffsets) {
+		return Node{}, errors.New("level out of range for node store")
+	}
+	var node Node
+	if _, err := fs.f.ReadAt(node.data[:], fs.offset(lvl, idx)); err != nil {
+		return Node{}, fmt.Errorf("reading node: %w", err)
+	}
+	return node, nil
+}
+
+// Close closes the backing file. Once closed, the store's PutNode/GetNode
+// methods must not be called again.
+func (fs *fileNodeStore) Close() error {
+	return fs.f.Close()
+}
+
+// pendingSubtree is one entry of a StreamingTree's mountain range: a
+// fully-computed subtree root together with its height above the leaf level.
+type pendingSubtree struct {
+	root   Node
+	height int
+}
+
+// StreamingTree incrementally builds a Merkle tree without requiring the
+// full leaf slice to be held in memory at once, which is a hard blocker when
+// aggregators build CommDA over multi-GB pieces. It maintains a compact
+// "mountain range" of at most one pending subtree root per height
+// (floor(log2(expectedLeaves))+1 in all),
+// combining two of equal height as soon as they appear, mirroring the
+// RFC 6962-style incremental construction used by Certificate Transparency
+// log implementations.
+type StreamingTree struct {
+	expectedLeaves int
+	pushed         int
+	leafLevel      int
+	pending        []pendingSubtree
+	// levelCount[h] tracks how many subtree roots of height h have been
+	// emitted so far, giving the deterministic index to store the next one at.
+	levelCount []int
+	store      NodeStore
+	hasher     Hasher
+}
+
+// NewStreamingTree creates a StreamingTree expecting exactly expectedLeaves
+// leafs to be pushed before Finalize is called. Computed nodes are kept in
+// memory and hashed with the package's default Hasher.
+func NewStreamingTree(expectedLeaves int) (*StreamingTree, error) {
+	if expectedLeaves <= 0 {
+		return nil, errors.New("expected leafs must be positive")
+	}
+	return NewStreamingTreeWithStore(expectedLeaves, newMemoryNodeStore(1+log2Ceil(expectedLeaves)))
+}
+
+// NewStreamingTreeWithStore is like NewStreamingTree but streams computed
+// nodes to store (e.g. one created with NewFileNodeStore) instead of
+// keeping them in memory, so that ConstructProof can still be served later
+// without ever materializing the whole tree in RAM.
+func NewStreamingTreeWithStore(expectedLeaves int, store NodeStore) (*StreamingTree, error) {
+	if expectedLeaves <= 0 {
+		return nil, errors.New("expected leafs must be positive")
+	}
+	leafLevel := log2Ceil(expectedLeaves)
+	return &StreamingTree{
+		expectedLeaves: expectedLeaves,
+		leafLevel:      leafLevel,
+		levelCount:     make([]int, leafLevel+1),
+		store:          store,
+		hasher:         defaultHasher,
+	}, nil
+}
+
+// Close releases the resources held by the StreamingTree's backing
+// NodeStore (e.g. the file opened by NewFileNodeStore). It is only needed
+// if the StreamingTree is abandoned before Finalize; Finalize hands the
+// store off to the returned StoredTree, whose Close should be used instead.
+func (s *StreamingTree) Close() error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Close()
+}
+
+// PushLeaf hashes data and appends it as the next leaf of the tree.
+func (s *StreamingTree) PushLeaf(data []byte) error {
+	return s.PushHashedLeaf(s.hasher.HashLeaf(data))
+}
+
+// PushHashedLeaf appends an already-hashed leaf to the tree.
+func (s *StreamingTree) PushHashedLeaf(leaf Node) error {
+	if s.pushed >= s.expectedLeaves {
+		return fmt.Errorf("streaming tree already has the expected %d leafs", s.expectedLeaves)
+	}
+	s.pushed++
+	if err := s.emit(leaf, 0); err != nil {
+		return err
+	}
+	return s.collapse()
+}
+
+// emit stores node at the given height at its next deterministic index and
+// pushes it onto the mountain range as the most recently produced subtree.
+func (s *StreamingTree) emit(node Node, height int) error {
+	idx := s.levelCount[height]
+	s.levelCount[height]++
+	if s.store != nil {
+		if err := s.store.PutNode(s.leafLevel-height, idx, node); err != nil {
+			return fmt.Errorf("storing node: %w", err)
+		}
+	}
+	s.pending = append(s.pending, pendingSubtree{root: node, height: height})
+	return nil
+}
+
+// collapse repeatedly combines the two most recently pushed subtrees while
+// they share the same height, so the mountain range never holds more than
+// one pending root per height.
+func (s *StreamingTree) collapse() error {
+	for len(s.pending) >= 2 {
+		top := s.pending[len(s.pending)-1]
+		second := s.pending[len(s.pending)-2]
+		if top.height != second.height {
+			break
+		}
+		combined := *computeNode(&second.root, &top.root, s.hasher)
+		s.pending = s.pending[:len(s.pending)-2]
+		if err := s.emit(combined, top.height+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finalize completes the tree once all expectedLeaves have been pushed. Any
+// subtree left without an equal-height sibling becomes its own parent via
+// hasher.HashLeaf, exactly as growTreeHashedLeafs does for a trailing odd
+// node, until a single root remains.
+func (s *StreamingTree) Finalize() (*StoredTree, error) {
+	if s.pushed != s.expectedLeaves {
+		return nil, fmt.Errorf("streaming tree expected %d leafs but only %d were pushed", s.expectedLeaves, s.pushed)
+	}
+	for len(s.pending) > 1 {
+		last := s.pending[len(s.pending)-1]
+		s.pending = s.pending[:len(s.pending)-1]
+		promoted := s.hasher.HashLeaf(last.root.data[:])
+		if err := s.emit(promoted, last.height+1); err != nil {
+			return nil, err
+		}
+		if err := s.collapse(); err != nil {
+			return nil, err
+		}
+	}
+	root := s.pending[0].root
+	if s.store != nil {
+		if err := s.store.PutNode(0, 0, root); err != nil {
+			return nil, fmt.Errorf("storing root: %w", err)
+		}
+	}
+	return &StoredTree{depth: s.leafLevel + 1, leafs: s.expectedLeaves, store: s.store, root: root, hasherID: s.hasher.ID()}, nil
+}
+
+// StoredTree is a Merkle tree whose nodes live in a NodeStore rather than an
+// in-memory TreeData, allowing proofs to be served for trees built via a
+// StreamingTree without ever holding the whole tree in RAM.
+type StoredTree struct {
+	depth    int
+	leafs    int
+	store    NodeStore
+	root     Node
+	hasherID HasherID
+}
+
+// Close releases the resources held by the StoredTree's backing NodeStore
+// (e.g. the file opened by NewFileNodeStore). Once closed, ConstructProof
+// must not be called again.
+func (s *StoredTree) Close() error {
+	return s.store.Close()
+}
+
+func (s *StoredTree) Depth() int { return s.depth }
+
+func (s *StoredTree) Leafs() int { return s.leafs }
+
+func (s *StoredTree) GetRoot() *Node {
+	root := s.root
+	return &root
+}
+
+// ConstructProof constructs a Merkle proof of the node at level lvl and
+// index idx, reading only the sibling path from the backing NodeStore.
+func (s *StoredTree) ConstructProof(lvl int, idx int) (ProofData, error) {
+	if lvl < 1 || lvl >= s.depth {
+		log.Println("level is either below 1 or bigger than the tree supports")
+		return ProofData{}, errors.New("level is either below 1 or bigger than the tree supports")
+	}
+	if idx < 0 {
+		log.Println(fmt.Sprintf("the requested index %d is negative", idx))
+		return ProofData{}, errors.New(fmt.Sprintf("the requested index %d is negative", idx))
+	}
+	proof := make([]Node, lvl)
+	currentIdx := idx
+	for currentLvl := lvl; currentLvl >= 1; currentLvl-- {
+		sibIdx := getSiblingIdx(currentIdx)
+		if sibling, err := s.store.GetNode(currentLvl, sibIdx); err == nil {
+			proof[currentLvl-1] = sibling
+		}
+		currentIdx = currentIdx / 2
+	}
+	return ProofData{path: proof, lvl: lvl, idx: idx, hasherID: s.hasherID}, nil
+}
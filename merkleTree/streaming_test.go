@@ -0,0 +1,116 @@
+package merkleTree
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestStreamingTreeMatchesGrowTree checks that a StreamingTree built leaf by
+// leaf, including non-power-of-two leaf counts, produces the same root and
+// per-leaf proofs as building the whole tree at once with GrowTree.
+func TestStreamingTreeMatchesGrowTree(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8, 13} {
+		data := leafData(n)
+		want, err := GrowTree(data)
+		if err != nil {
+			t.Fatalf("leafs=%d: GrowTree: %v", n, err)
+		}
+
+		streaming, err := NewStreamingTree(n)
+		if err != nil {
+			t.Fatalf("leafs=%d: NewStreamingTree: %v", n, err)
+		}
+		for _, leaf := range data {
+			if err := streaming.PushLeaf(leaf); err != nil {
+				t.Fatalf("leafs=%d: PushLeaf: %v", n, err)
+			}
+		}
+		stored, err := streaming.Finalize()
+		if err != nil {
+			t.Fatalf("leafs=%d: Finalize: %v", n, err)
+		}
+
+		if *stored.GetRoot() != *want.GetRoot() {
+			t.Fatalf("leafs=%d: streaming root %x != GrowTree root %x", n, stored.GetRoot().data, want.GetRoot().data)
+		}
+		if stored.Depth() != want.Depth() {
+			t.Fatalf("leafs=%d: streaming depth %d != GrowTree depth %d", n, stored.Depth(), want.Depth())
+		}
+
+		if n > 1 {
+			for idx := 0; idx < n; idx++ {
+				proof, err := stored.ConstructProof(stored.Depth()-1, idx)
+				if err != nil {
+					t.Fatalf("leafs=%d idx=%d: ConstructProof: %v", n, idx, err)
+				}
+				if !proof.ValidateLeaf(data[idx], stored.GetRoot()) {
+					t.Fatalf("leafs=%d idx=%d: streaming proof failed to validate", n, idx)
+				}
+			}
+		}
+		if err := stored.Close(); err != nil {
+			t.Fatalf("leafs=%d: Close: %v", n, err)
+		}
+	}
+}
+
+// TestStreamingTreeRejectsTooManyLeafs checks that pushing past the declared
+// leaf count is rejected rather than silently overflowing the tree.
+func TestStreamingTreeRejectsTooManyLeafs(t *testing.T) {
+	streaming, err := NewStreamingTree(2)
+	if err != nil {
+		t.Fatalf("NewStreamingTree: %v", err)
+	}
+	if err := streaming.PushLeaf([]byte("a")); err != nil {
+		t.Fatalf("PushLeaf: %v", err)
+	}
+	if err := streaming.PushLeaf([]byte("b")); err != nil {
+		t.Fatalf("PushLeaf: %v", err)
+	}
+	if err := streaming.PushLeaf([]byte("c")); err == nil {
+		t.Fatal("expected pushing a third leaf to a 2-leaf StreamingTree to fail")
+	}
+}
+
+// TestFileNodeStoreMatchesMemory checks that a StreamingTree backed by a
+// fileNodeStore produces the same root and proofs as the in-memory default,
+// and that the backing file can be closed afterwards.
+func TestFileNodeStoreMatchesMemory(t *testing.T) {
+	data := leafData(5)
+	want, err := GrowTree(data)
+	if err != nil {
+		t.Fatalf("GrowTree: %v", err)
+	}
+
+	store, err := NewFileNodeStore(filepath.Join(t.TempDir(), "tree.bin"), len(data))
+	if err != nil {
+		t.Fatalf("NewFileNodeStore: %v", err)
+	}
+	streaming, err := NewStreamingTreeWithStore(len(data), store)
+	if err != nil {
+		t.Fatalf("NewStreamingTreeWithStore: %v", err)
+	}
+	for _, leaf := range data {
+		if err := streaming.PushLeaf(leaf); err != nil {
+			t.Fatalf("PushLeaf: %v", err)
+		}
+	}
+	stored, err := streaming.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize: %v", err)
+	}
+
+	if *stored.GetRoot() != *want.GetRoot() {
+		t.Fatalf("file-backed root %x != GrowTree root %x", stored.GetRoot().data, want.GetRoot().data)
+	}
+	proof, err := stored.ConstructProof(stored.Depth()-1, 2)
+	if err != nil {
+		t.Fatalf("ConstructProof: %v", err)
+	}
+	if !proof.ValidateLeaf(data[2], stored.GetRoot()) {
+		t.Fatal("file-backed proof failed to validate")
+	}
+	if err := stored.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}